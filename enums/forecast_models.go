@@ -1,9 +0,0 @@
-package enums
-
-type ForecastModel string
-
-const (
-	LINEAR      ForecastModel = "linear"
-	LOGARITHMIC               = "logarithmic"
-	POLYNOMIAL                = "polynomial"
-)
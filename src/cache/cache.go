@@ -0,0 +1,96 @@
+// Package cache computes the cache key used to store and retrieve already
+// calculated forecasts in the S3-compatible object storage and provides
+// small helpers to read and write those objects
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/minio/minio-go/v7"
+
+	"microservice/structs"
+)
+
+// fingerprint is the canonical, hashable representation of a calculation
+// request. keeping it separate from structs.CalculationRequest decouples the
+// cache key from json tag changes made to the wire format
+type fingerprint struct {
+	Model           string   `json:"model"`
+	Keys            []string `json:"keys"`
+	ConsumerGroups  []string `json:"consumerGroups"`
+	ForecastedYears int      `json:"forecastedYears"`
+	HistoricalYears int      `json:"historicalYears"`
+	ConfidenceLevel float64  `json:"confidenceLevel"`
+}
+
+// Key computes a deterministic cache key for the supplied calculation
+// request. Keys and consumer groups are sorted before hashing so that
+// requests differing only in query parameter order share the same key
+func Key(request structs.CalculationRequest) (string, error) {
+	keys := append([]string(nil), request.Keys...)
+	sort.Strings(keys)
+	consumerGroups := append([]string(nil), request.ConsumerGroups...)
+	sort.Strings(consumerGroups)
+
+	var confidenceLevel float64
+	if request.ConfidenceLevel != nil {
+		confidenceLevel = *request.ConfidenceLevel
+	}
+
+	canonical, err := json.Marshal(fingerprint{
+		Model:           string(request.Model),
+		Keys:            keys,
+		ConsumerGroups:  consumerGroups,
+		ForecastedYears: request.ForecastedYears,
+		HistoricalYears: request.HistoricalYears,
+		ConfidenceLevel: confidenceLevel,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Get returns the cached forecast result stored under key, if present. The
+// returned boolean is false if the object does not exist in the bucket
+func Get(ctx context.Context, client *minio.Client, bucket string, key string) ([]byte, bool, error) {
+	object, err := client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, false, err
+	}
+	defer object.Close()
+
+	if _, statErr := object.Stat(); statErr != nil {
+		errResponse := minio.ToErrorResponse(statErr)
+		if errResponse.Code == "NoSuchKey" {
+			return nil, false, nil
+		}
+		return nil, false, statErr
+	}
+
+	content, err := io.ReadAll(object)
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// Put uploads the forecast result to the bucket under key, tagging the
+// object with the model it was calculated with
+func Put(ctx context.Context, client *minio.Client, bucket string, key string, model string, content []byte) error {
+	_, err := client.PutObject(ctx, bucket, key, bytes.NewReader(content), int64(len(content)), minio.PutObjectOptions{
+		ContentType: "application/json",
+		UserMetadata: map[string]string{
+			"Model": model,
+		},
+	})
+	return err
+}
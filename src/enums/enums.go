@@ -5,3 +5,4 @@ type ForecastModel string
 const LinearForecast ForecastModel = "linear"
 const PolynomialForecast ForecastModel = "polynomial"
 const LogarithmicForecast ForecastModel = "logarithmic"
+const ExponentialSmoothingForecast ForecastModel = "holt-winters"
@@ -1,9 +1,8 @@
 package enums
 
-type ForecastModel string
-
 const (
-	LINEAR_FORECAST      ForecastModel = "linear"
-	LOGARITHMIC_FORECAST ForecastModel = "logarithmic"
-	POLYNOMIAL_FORECAST  ForecastModel = "polynomial"
+	LINEAR_FORECAST       ForecastModel = "linear"
+	LOGARITHMIC_FORECAST  ForecastModel = "logarithmic"
+	POLYNOMIAL_FORECAST   ForecastModel = "polynomial"
+	HOLT_WINTERS_FORECAST ForecastModel = "holt-winters"
 )
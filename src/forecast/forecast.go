@@ -0,0 +1,68 @@
+package forecast
+
+import (
+	"fmt"
+
+	"microservice/metrics"
+	"microservice/structs"
+	"microservice/vars"
+)
+
+// seasonalPeriod is the period used for the Holt-Winters seasonal component.
+// annual water consumption data has no meaningful intra-year season, so a
+// period of 1 (i.e. no seasonality) is used
+const seasonalPeriod = 1
+
+// Compute calculates a forecast for every key/consumer group combination in
+// request using the in-process Holt-Winters implementation instead of
+// dispatching the request to the external calculation module
+func Compute(request structs.CalculationRequest) (structs.ForecastResult, error) {
+	result := structs.ForecastResult{Model: request.Model}
+
+	for _, key := range request.Keys {
+		for _, consumerGroup := range request.ConsumerGroups {
+			stopQueryTimer := metrics.TimeQuery("get-historical-consumption")
+			series, err := historicalConsumption(key, consumerGroup)
+			stopQueryTimer()
+			if err != nil {
+				return structs.ForecastResult{}, err
+			}
+
+			values, warning, err := HoltWinters(series, seasonalPeriod, request.ForecastedYears)
+			if err != nil {
+				return structs.ForecastResult{}, fmt.Errorf("unable to fit holt-winters model for %s/%s: %w", key, consumerGroup, err)
+			}
+
+			result.Series = append(result.Series, structs.ForecastSeries{
+				Key:           key,
+				ConsumerGroup: consumerGroup,
+				Values:        values,
+				Warning:       warning,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// historicalConsumption loads the yearly water consumption of key/
+// consumerGroup ordered by year, oldest first, which is the series the
+// Holt-Winters fit is run against
+func historicalConsumption(key string, consumerGroup string) ([]float64, error) {
+	rows, err := vars.SqlQueries.Query(vars.PostgresConnection, "get-historical-consumption", key, consumerGroup)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var series []float64
+	for rows.Next() {
+		var consumption float64
+		if err := rows.Scan(&consumption); err != nil {
+			return nil, err
+		}
+		series = append(series, consumption)
+	}
+
+	return series, nil
+}
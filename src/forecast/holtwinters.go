@@ -0,0 +1,113 @@
+// Package forecast implements an in-process fallback forecast calculation
+// used when the external calculation module should not be called for a
+// model, e.g. enums.ExponentialSmoothingForecast
+package forecast
+
+import "fmt"
+
+// gridStep is the resolution used when fitting alpha, beta and gamma by grid
+// search over the open interval (0, 1): the candidates run from gridStep to
+// 1-gridStep, and the endpoints 0 and 1 are not tried
+const gridStep = 0.05
+
+// HoltWinters fits an additive Holt-Winters model to series and returns the
+// forecast for the next horizon steps. period is the seasonal period (use 1
+// for data without a meaningful season, e.g. annual water consumption).
+// Series shorter than 2*period fall back to simple exponential smoothing and
+// the returned warning is non-empty
+func HoltWinters(series []float64, period int, horizon int) (values []float64, warning string, err error) {
+	if len(series) == 0 {
+		return nil, "", fmt.Errorf("forecast: series must not be empty")
+	}
+	if period < 1 {
+		period = 1
+	}
+
+	if len(series) < 2*period {
+		values := simpleExponentialSmoothing(series, horizon)
+		return values, "series is shorter than two seasonal periods; fell back to simple exponential smoothing", nil
+	}
+
+	bestSSE := -1.0
+	var bestAlpha, bestBeta, bestGamma float64
+	for alpha := gridStep; alpha < 1; alpha += gridStep {
+		for beta := gridStep; beta < 1; beta += gridStep {
+			for gamma := gridStep; gamma < 1; gamma += gridStep {
+				_, _, _, sse := fit(series, period, alpha, beta, gamma)
+				if bestSSE < 0 || sse < bestSSE {
+					bestSSE = sse
+					bestAlpha, bestBeta, bestGamma = alpha, beta, gamma
+				}
+			}
+		}
+	}
+
+	level, trend, seasonal, _ := fit(series, period, bestAlpha, bestBeta, bestGamma)
+	n := len(series)
+	forecast := make([]float64, horizon)
+	for h := 1; h <= horizon; h++ {
+		seasonalIndex := seasonal[n-period+((h-1)%period)]
+		forecast[h-1] = level + float64(h)*trend + seasonalIndex
+	}
+
+	return forecast, "", nil
+}
+
+// fit runs one pass of additive Holt-Winters over series with the supplied
+// smoothing parameters and returns the final level, trend, the seasonal
+// index series and the sum of squared one-step-ahead errors
+func fit(series []float64, period int, alpha float64, beta float64, gamma float64) (level float64, trend float64, seasonal []float64, sse float64) {
+	level = mean(series[:period])
+	trend = (mean(series[period:2*period]) - mean(series[:period])) / float64(period)
+
+	seasonal = make([]float64, len(series))
+	for i := 0; i < period; i++ {
+		seasonal[i] = series[i] - level
+	}
+
+	for t := period; t < len(series); t++ {
+		forecastValue := level + trend + seasonal[t-period]
+		sse += (series[t] - forecastValue) * (series[t] - forecastValue)
+
+		previousLevel := level
+		level = alpha*(series[t]-seasonal[t-period]) + (1-alpha)*(previousLevel+trend)
+		trend = beta*(level-previousLevel) + (1-beta)*trend
+		seasonal[t] = gamma*(series[t]-level) + (1-gamma)*seasonal[t-period]
+	}
+
+	return level, trend, seasonal, sse
+}
+
+// simpleExponentialSmoothing is used when the series is too short to fit a
+// seasonal component. It fits a single smoothing parameter by grid search
+// and repeats the last smoothed level for every step of the horizon
+func simpleExponentialSmoothing(series []float64, horizon int) []float64 {
+	bestSSE := -1.0
+	bestLevel := series[0]
+	for alpha := gridStep; alpha < 1; alpha += gridStep {
+		level := series[0]
+		sse := 0.0
+		for _, value := range series[1:] {
+			sse += (value - level) * (value - level)
+			level = alpha*value + (1-alpha)*level
+		}
+		if bestSSE < 0 || sse < bestSSE {
+			bestSSE = sse
+			bestLevel = level
+		}
+	}
+
+	forecast := make([]float64, horizon)
+	for h := range forecast {
+		forecast[h] = bestLevel
+	}
+	return forecast
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
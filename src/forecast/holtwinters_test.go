@@ -0,0 +1,83 @@
+package forecast
+
+import (
+	"testing"
+)
+
+// TestHoltWinters_KnownSeries exercises HoltWinters against short,
+// hand-computed series. Each series is either perfectly constant (so every
+// candidate alpha/beta/gamma from the grid search yields zero error and the
+// recurrence's fixed point is trivial to verify by hand) or short enough to
+// trigger the simple-exponential-smoothing fallback, so the expected values
+// don't depend on which grid point the search happens to settle on
+func TestHoltWinters_KnownSeries(t *testing.T) {
+	tests := []struct {
+		name            string
+		series          []float64
+		period          int
+		horizon         int
+		wantValues      []float64
+		wantWarningText string
+	}{
+		{
+			name:       "constant series, period 1",
+			series:     []float64{5, 5, 5, 5, 5, 5},
+			period:     1,
+			horizon:    3,
+			wantValues: []float64{5, 5, 5},
+		},
+		{
+			name:       "constant series, period 2",
+			series:     []float64{3, 3, 3, 3, 3, 3},
+			period:     2,
+			horizon:    2,
+			wantValues: []float64{3, 3},
+		},
+		{
+			name:            "single point falls back to simple exponential smoothing",
+			series:          []float64{7},
+			period:          1,
+			horizon:         3,
+			wantValues:      []float64{7, 7, 7},
+			wantWarningText: "series is shorter than two seasonal periods; fell back to simple exponential smoothing",
+		},
+		{
+			name:            "shorter than two seasonal periods falls back",
+			series:          []float64{2, 2},
+			period:          3,
+			horizon:         2,
+			wantValues:      []float64{2, 2},
+			wantWarningText: "series is shorter than two seasonal periods; fell back to simple exponential smoothing",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			values, warning, err := HoltWinters(testCase.series, testCase.period, testCase.horizon)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if warning != testCase.wantWarningText {
+				t.Errorf("warning = %q, want %q", warning, testCase.wantWarningText)
+			}
+
+			if len(values) != len(testCase.wantValues) {
+				t.Fatalf("got %d values, want %d", len(values), len(testCase.wantValues))
+			}
+			for i, want := range testCase.wantValues {
+				if values[i] != want {
+					t.Errorf("values[%d] = %v, want %v", i, values[i], want)
+				}
+			}
+		})
+	}
+}
+
+// TestHoltWinters_EmptySeries asserts that an empty series is rejected
+// instead of panicking on the level/trend computation
+func TestHoltWinters_EmptySeries(t *testing.T) {
+	if _, _, err := HoltWinters(nil, 1, 1); err == nil {
+		t.Error("expected an error for an empty series, got nil")
+	}
+}
@@ -0,0 +1,394 @@
+// Package jobs implements the batch forecast job API: POST /forecasts
+// accepts many {model, keys, consumerGroups} combinations at once, persists
+// each as a sub-result row in Postgres and publishes one AMQP message per
+// combination under its own correlation id. A single background consumer,
+// started once from main, matches replies back to their sub-result by
+// correlation id and persists the result, decoupling delivery from any one
+// HTTP request
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"microservice/enums"
+	"microservice/utils"
+	"microservice/vars"
+)
+
+// replyQueueName is the durable, shared queue every batch job reply is
+// published to. it is separate from the calculation reply queue declared in
+// microservice/transport, long-lived and consumed by a single background
+// goroutine for the lifetime of the process
+const replyQueueName = "water-usage-forecasts.batch-results"
+
+// job and sub-result state values stored in Postgres
+const (
+	StatePending   = "pending"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+)
+
+// pendingResultTimeout bounds how long a sub-result may stay pending: if the
+// calculation module never replies, failIfStillPending marks it failed
+// instead of leaving it - and its parent job - stuck forever
+const pendingResultTimeout = 5 * time.Minute
+
+// SubRequest describes a single forecast calculation within a batch job
+type SubRequest struct {
+	Model          enums.ForecastModel `json:"model"`
+	Keys           []string            `json:"keys"`
+	ConsumerGroups []string            `json:"consumerGroups"`
+}
+
+// SubResult tracks the state of a single SubRequest within a job
+type SubResult struct {
+	ID      string          `json:"id"`
+	Request SubRequest      `json:"request"`
+	State   string          `json:"state"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// Job is the manifest returned for a batch forecast request and the document
+// returned by the polling endpoints
+type Job struct {
+	ID        string      `json:"id"`
+	State     string      `json:"state"`
+	Results   []SubResult `json:"results"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+// sentinel errors returned by Validate, mapped to request errors by
+// routes.CreateForecastJob the same way ForecastRequest maps its own
+// validation failures
+var (
+	ErrUnsupportedModel     = errors.New("unsupported forecast model")
+	ErrMissingKeys          = errors.New("missing shape keys")
+	ErrInvalidKey           = errors.New("invalid shape key")
+	ErrInvalidConsumerGroup = errors.New("invalid consumer group")
+)
+
+// Validate checks a single sub-request the same way ForecastRequest
+// validates its query parameters: the model must be one of the supported
+// forecast methods, and every shape key and consumer group must exist in
+// the database. Consumer groups are optional; unlike ForecastRequest, which
+// fills in every known consumer group when none are given, omitted consumer
+// groups are passed on empty and left unfiltered by the calculation module
+func Validate(request SubRequest) error {
+	if request.Model != enums.LinearForecast && request.Model != enums.PolynomialForecast &&
+		request.Model != enums.LogarithmicForecast && request.Model != enums.ExponentialSmoothingForecast {
+		return ErrUnsupportedModel
+	}
+
+	if len(request.Keys) == 0 {
+		return ErrMissingKeys
+	}
+
+	knownKeyRows, err := vars.SqlQueries.Query(vars.PostgresConnection, "check-municipality-keys", pq.Array(request.Keys))
+	if err != nil {
+		return err
+	}
+	defer knownKeyRows.Close()
+	var knownKeys []string
+	for knownKeyRows.Next() {
+		var knownKey string
+		if scanErr := knownKeyRows.Scan(&knownKey); scanErr != nil {
+			return scanErr
+		}
+		knownKeys = append(knownKeys, knownKey)
+	}
+	for _, key := range request.Keys {
+		if !utils.ArrayContains(knownKeys, key) {
+			return ErrInvalidKey
+		}
+	}
+
+	if len(request.ConsumerGroups) == 0 {
+		return nil
+	}
+
+	knownConsumerGroupRows, err := vars.SqlQueries.Query(vars.PostgresConnection, "check-consumer-groups", pq.Array(request.ConsumerGroups))
+	if err != nil {
+		return err
+	}
+	defer knownConsumerGroupRows.Close()
+	var knownConsumerGroups []string
+	for knownConsumerGroupRows.Next() {
+		var knownConsumerGroup string
+		if scanErr := knownConsumerGroupRows.Scan(&knownConsumerGroup); scanErr != nil {
+			return scanErr
+		}
+		knownConsumerGroups = append(knownConsumerGroups, knownConsumerGroup)
+	}
+	for _, consumerGroup := range request.ConsumerGroups {
+		if !utils.ArrayContains(knownConsumerGroups, consumerGroup) {
+			return ErrInvalidConsumerGroup
+		}
+	}
+
+	return nil
+}
+
+// Create persists a new job with one sub-result per request and publishes a
+// calculation message for each of them, returning the job manifest
+// immediately without waiting for any of them to complete
+func Create(requests []SubRequest) (Job, error) {
+	job := Job{
+		ID:        uuid.NewString(),
+		State:     StatePending,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := vars.SqlQueries.Exec(vars.PostgresConnection, "insert-forecast-job", job.ID, job.State, job.CreatedAt)
+	if err != nil {
+		return Job{}, err
+	}
+
+	var failedSubResultIDs []string
+	for _, subRequest := range requests {
+		subResult := SubResult{
+			ID:      uuid.NewString(),
+			Request: subRequest,
+			State:   StatePending,
+		}
+
+		payloadJSON, marshalErr := json.Marshal(subResult.Request)
+		if marshalErr != nil {
+			return Job{}, marshalErr
+		}
+
+		_, err := vars.SqlQueries.Exec(vars.PostgresConnection, "insert-forecast-job-subresult",
+			subResult.ID, job.ID, subResult.State, payloadJSON)
+		if err != nil {
+			return Job{}, err
+		}
+
+		if err := publish(subRequest, subResult.ID); err != nil {
+			vars.HttpLogger.Error().Err(err).Str("subResultId", subResult.ID).
+				Msg("unable to publish batch forecast sub-request, marking it failed")
+			failSubResult(subResult.ID, err)
+			failedSubResultIDs = append(failedSubResultIDs, subResult.ID)
+		} else {
+			go failIfStillPending(subResult.ID)
+		}
+
+		job.Results = append(job.Results, subResult)
+	}
+
+	// only refresh the parent job's state once every sub-result has been
+	// inserted: doing it from inside the loop above would have the pending
+	// count only see rows inserted so far, and could mark the job terminal
+	// while siblings further down the batch are still pending
+	if len(failedSubResultIDs) > 0 {
+		if err := refreshJobState(failedSubResultIDs[0]); err != nil {
+			vars.HttpLogger.Warn().Err(err).Str("jobId", job.ID).
+				Msg("unable to refresh the parent job state")
+		}
+	}
+
+	return job, nil
+}
+
+// publish sends a single sub-request to the calculation module, using the
+// sub-result id as the correlation id and the shared reply queue declared in
+// StartResultConsumer as the reply-to address
+func publish(request SubRequest, subResultID string) error {
+	message, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return vars.AMQP.Channel.PublishWithContext(ctx,
+		vars.Environment["AMQP_EXCHANGE"], vars.Environment["CALCULATION_MODULE_ROUTING_KEY"], false, false,
+		amqp.Publishing{
+			ContentType:   "application/json",
+			Body:          message,
+			CorrelationId: subResultID,
+			ReplyTo:       replyQueueName,
+		})
+}
+
+// Get loads a job and all of its sub-results
+func Get(jobID string) (Job, bool, error) {
+	row, err := vars.SqlQueries.QueryRow(vars.PostgresConnection, "get-forecast-job", jobID)
+	if err != nil {
+		return Job{}, false, err
+	}
+
+	var job Job
+	if scanErr := row.Scan(&job.ID, &job.State, &job.CreatedAt); scanErr != nil {
+		return Job{}, false, nil
+	}
+
+	rows, err := vars.SqlQueries.Query(vars.PostgresConnection, "get-forecast-job-subresults", jobID)
+	if err != nil {
+		return Job{}, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subResult SubResult
+		var requestJSON, resultJSON []byte
+		if scanErr := rows.Scan(&subResult.ID, &subResult.State, &requestJSON, &resultJSON, &subResult.Error); scanErr != nil {
+			return Job{}, false, scanErr
+		}
+		if unmarshalErr := json.Unmarshal(requestJSON, &subResult.Request); unmarshalErr != nil {
+			return Job{}, false, unmarshalErr
+		}
+		if len(resultJSON) > 0 {
+			subResult.Result = resultJSON
+		}
+		job.Results = append(job.Results, subResult)
+	}
+
+	return job, true, nil
+}
+
+// GetSubResult loads a single sub-result of jobID
+func GetSubResult(jobID string, subID string) (SubResult, bool, error) {
+	row, err := vars.SqlQueries.QueryRow(vars.PostgresConnection, "get-forecast-job-subresult", jobID, subID)
+	if err != nil {
+		return SubResult{}, false, err
+	}
+
+	var subResult SubResult
+	var requestJSON, resultJSON []byte
+	if scanErr := row.Scan(&subResult.ID, &subResult.State, &requestJSON, &resultJSON, &subResult.Error); scanErr != nil {
+		return SubResult{}, false, nil
+	}
+	if unmarshalErr := json.Unmarshal(requestJSON, &subResult.Request); unmarshalErr != nil {
+		return SubResult{}, false, unmarshalErr
+	}
+	if len(resultJSON) > 0 {
+		subResult.Result = resultJSON
+	}
+
+	return subResult, true, nil
+}
+
+// StartResultConsumer declares the shared reply queue and consumes it for
+// the lifetime of ctx, writing every reply into the matching sub-result row
+// by its correlation id. It should be started once from main after the AMQP
+// connection has been established
+func StartResultConsumer(ctx context.Context) error {
+	queue, err := vars.AMQP.Channel.QueueDeclare(replyQueueName, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := vars.AMQP.Channel.Consume(queue.Name, "water-usage-forecasts.batch-consumer", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case delivery := <-deliveries:
+				handleReply(delivery)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func handleReply(delivery amqp.Delivery) {
+	_, err := vars.SqlQueries.Exec(vars.PostgresConnection, "finish-forecast-job-subresult",
+		StateSucceeded, delivery.Body, "", delivery.CorrelationId)
+	if err != nil {
+		vars.HttpLogger.Error().Err(err).Str("subResultId", delivery.CorrelationId).
+			Msg("unable to persist batch forecast sub-result")
+		return
+	}
+
+	if err := refreshJobState(delivery.CorrelationId); err != nil {
+		vars.HttpLogger.Warn().Err(err).Str("subResultId", delivery.CorrelationId).
+			Msg("unable to refresh the parent job state")
+	}
+}
+
+// failSubResult marks subResultID as failed, used when the sub-request could
+// not be published to the calculation module at all. The caller is
+// responsible for refreshing the parent job's state once every sub-result of
+// the batch has been inserted
+func failSubResult(subResultID string, publishErr error) {
+	_, err := vars.SqlQueries.Exec(vars.PostgresConnection, "finish-forecast-job-subresult",
+		StateFailed, nil, publishErr.Error(), subResultID)
+	if err != nil {
+		vars.HttpLogger.Error().Err(err).Str("subResultId", subResultID).
+			Msg("unable to persist batch forecast sub-result failure")
+	}
+}
+
+// failIfStillPending marks subResultID as failed if the calculation module
+// has not replied within pendingResultTimeout, using a conditional update so
+// a late reply racing against the timeout cannot overwrite a real result.
+// Without this, a lost message or a stuck calculation module leaves the
+// sub-result - and its parent job - pending forever
+func failIfStillPending(subResultID string) {
+	time.Sleep(pendingResultTimeout)
+
+	result, err := vars.SqlQueries.Exec(vars.PostgresConnection, "fail-pending-forecast-job-subresult",
+		StateFailed, "calculation module did not respond in time", subResultID)
+	if err != nil {
+		vars.HttpLogger.Error().Err(err).Str("subResultId", subResultID).
+			Msg("unable to mark stale batch forecast sub-result as failed")
+		return
+	}
+	if affected, affectedErr := result.RowsAffected(); affectedErr != nil || affected == 0 {
+		return
+	}
+
+	if err := refreshJobState(subResultID); err != nil {
+		vars.HttpLogger.Warn().Err(err).Str("subResultId", subResultID).
+			Msg("unable to refresh the parent job state")
+	}
+}
+
+// refreshJobState marks the parent job of subResultID as finished - failed if
+// any of its sub-results failed, succeeded otherwise - once none of its
+// sub-results are pending or running anymore
+func refreshJobState(subResultID string) error {
+	row, err := vars.SqlQueries.QueryRow(vars.PostgresConnection, "count-pending-job-subresults-by-subresult", subResultID)
+	if err != nil {
+		return err
+	}
+	var pending int
+	if err := row.Scan(&pending); err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	failedRow, err := vars.SqlQueries.QueryRow(vars.PostgresConnection, "count-failed-job-subresults-by-subresult", subResultID)
+	if err != nil {
+		return err
+	}
+	var failed int
+	if err := failedRow.Scan(&failed); err != nil {
+		return err
+	}
+
+	finalState := StateSucceeded
+	if failed > 0 {
+		finalState = StateFailed
+	}
+
+	_, err = vars.SqlQueries.Exec(vars.PostgresConnection, "finish-forecast-job-by-subresult", finalState, subResultID)
+	return err
+}
@@ -0,0 +1,117 @@
+// Package metrics registers the Prometheus collectors exposed by this
+// microservice under the "wisdom_water_forecasts" namespace/subsystem so
+// operators can scrape it without any changes to httplog
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wisdom"
+const subsystem = "water_forecasts"
+
+// ForecastRequestsTotal counts every forecast request handled by this
+// service, labeled with the requested model and the outcome of the request
+var ForecastRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "forecast_requests_total",
+	Help:      "Total number of forecast requests handled, labeled by model and status",
+}, []string{"model", "status"})
+
+// ForecastDurationSeconds observes the time spent waiting for the
+// calculation module to answer a forecast request
+var ForecastDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "forecast_duration_seconds",
+	Help:      "Duration of the AMQP round-trip for a forecast calculation, labeled by model",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"model"})
+
+// ForecastShapeKeys observes the number of municipality/area keys requested
+// in a single forecast request
+var ForecastShapeKeys = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "forecast_shape_keys",
+	Help:      "Number of shape keys requested in a forecast request, labeled by model",
+	Buckets:   []float64{1, 2, 5, 10, 20, 50, 100},
+}, []string{"model"})
+
+// AMQPPublishFailuresTotal counts every failed attempt to publish a
+// calculation request to the message broker
+var AMQPPublishFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "amqp_publish_failures_total",
+	Help:      "Total number of failed attempts to publish a calculation request to the message broker",
+})
+
+// DbQueryDurationSeconds observes the time spent executing a named prepared
+// query against the database
+var DbQueryDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "db_query_duration_seconds",
+	Help:      "Duration of a database query, labeled by query name",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"query"})
+
+// httpRequestDurationSeconds observes the duration of every incoming HTTP
+// request, labeled by route pattern and status code
+var httpRequestDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "http_request_duration_seconds",
+	Help:      "Duration of incoming HTTP requests, labeled by route pattern and status",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"pattern", "status"})
+
+// TimeQuery starts timing a named query against vars.SqlQueries and returns a
+// function which observes the elapsed duration once the query has returned,
+// e.g. `defer metrics.TimeQuery("check-municipality-keys")()`
+func TimeQuery(name string) func() {
+	start := time.Now()
+	return func() {
+		DbQueryDurationSeconds.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler exposes the registered collectors for scraping
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// statusRecorder wraps a http.ResponseWriter to capture the status code
+// written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Middleware records the duration and status of every HTTP request against
+// the route pattern it was matched to
+func Middleware(routePattern func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(nextHandler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			nextHandler.ServeHTTP(recorder, r)
+			httpRequestDurationSeconds.
+				WithLabelValues(routePattern(r), strconv.Itoa(recorder.status)).
+				Observe(time.Since(start).Seconds())
+		})
+	}
+}
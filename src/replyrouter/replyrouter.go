@@ -0,0 +1,72 @@
+// Package replyrouter fans out AMQP deliveries arriving on the shared
+// forecast calculation reply queue (see microservice/transport) to
+// whichever goroutine registered that delivery's correlation id. It
+// replaces the previous pattern of declaring an exclusive, auto-deleted
+// response queue for every dispatched request, which meant every forecast
+// calculation cost the broker a QueueDeclare/Consume/QueueDelete round trip
+// of its own
+package replyrouter
+
+import (
+	"context"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"microservice/vars"
+)
+
+// deliveryBufferSize bounds how many not-yet-read deliveries Start will hold
+// for a single registered correlation id (e.g. a burst of streamed progress
+// events) before it starts dropping them for that id
+const deliveryBufferSize = 32
+
+var (
+	mu    sync.Mutex
+	chans = make(map[string]chan amqp.Delivery)
+)
+
+// Register creates and returns the channel deliveries carrying correlationId
+// will be forwarded to by Start. The caller must call Unregister, typically
+// via defer, once it is done reading from the returned channel
+func Register(correlationId string) chan amqp.Delivery {
+	ch := make(chan amqp.Delivery, deliveryBufferSize)
+	mu.Lock()
+	chans[correlationId] = ch
+	mu.Unlock()
+	return ch
+}
+
+// Unregister removes the channel registered for correlationId. Deliveries
+// for a correlation id with no registration are dropped by Start
+func Unregister(correlationId string) {
+	mu.Lock()
+	delete(chans, correlationId)
+	mu.Unlock()
+}
+
+// Start reads deliveries until ctx is done, forwarding each one to the
+// channel registered for its correlation id, if any. It should be run in its
+// own goroutine for the lifetime of the shared reply queue consumer,
+// typically started once from main via transport.StartReplyConsumer
+func Start(ctx context.Context, deliveries <-chan amqp.Delivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-deliveries:
+			mu.Lock()
+			ch, found := chans[delivery.CorrelationId]
+			mu.Unlock()
+			if !found {
+				continue
+			}
+			select {
+			case ch <- delivery:
+			default:
+				vars.HttpLogger.Warn().Str("correlationId", delivery.CorrelationId).
+					Msg("reply router dropped a delivery, receiver is not keeping up")
+			}
+		}
+	}
+}
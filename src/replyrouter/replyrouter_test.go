@@ -0,0 +1,77 @@
+package replyrouter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// TestStart_FansOutByCorrelationId fires many concurrent "requests", each
+// registering its own correlation id and publishing one delivery for that id
+// through a single shared channel, and asserts every request receives
+// exactly its own payload and none of anyone else's
+func TestStart_FansOutByCorrelationId(t *testing.T) {
+	const requestCount = 200
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries := make(chan amqp.Delivery)
+	go Start(ctx, deliveries)
+
+	var wg sync.WaitGroup
+	for i := 0; i < requestCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			correlationId := "request-" + strconv.Itoa(i)
+			ch := Register(correlationId)
+			defer Unregister(correlationId)
+
+			deliveries <- amqp.Delivery{CorrelationId: correlationId, Body: []byte(correlationId)}
+
+			select {
+			case delivery := <-ch:
+				if string(delivery.Body) != correlationId {
+					t.Errorf("request %s received a reply for %s", correlationId, string(delivery.Body))
+				}
+			case <-time.After(2 * time.Second):
+				t.Errorf("request %s timed out waiting for its reply", correlationId)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestStart_DropsUnregisteredDeliveries asserts that a delivery for a
+// correlation id nobody registered is silently dropped instead of blocking
+// the router or panicking
+func TestStart_DropsUnregisteredDeliveries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries := make(chan amqp.Delivery)
+	go Start(ctx, deliveries)
+
+	deliveries <- amqp.Delivery{CorrelationId: "nobody-is-waiting", Body: []byte("ignored")}
+
+	correlationId := "after-the-unregistered-delivery"
+	ch := Register(correlationId)
+	defer Unregister(correlationId)
+
+	deliveries <- amqp.Delivery{CorrelationId: correlationId, Body: []byte(correlationId)}
+
+	select {
+	case delivery := <-ch:
+		if string(delivery.Body) != correlationId {
+			t.Errorf("expected %s, got %s", correlationId, string(delivery.Body))
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("timed out waiting for the reply following the unregistered delivery")
+	}
+}
@@ -13,6 +13,18 @@ const UnsupportedForecastMethod = "UNSUPPORTED_FORECAST_METHOD"
 const MissingShapeKeys = "MISSING_SHAPE_KEYS"
 const InvalidShapeKey = "INVALID_SHAPE_KEY"
 const InvalidConsumerGroup = "INVALID_CONSUMER_GROUP"
+const InvalidForecastedYears = "INVALID_FORECASTED_YEARS"
+const InvalidHistoricalYears = "INVALID_HISTORICAL_YEARS"
+const InvalidConfidenceLevel = "INVALID_CONFIDENCE_LEVEL"
+const InvalidForecastJobBody = "INVALID_FORECAST_JOB_BODY"
+
+// the following codes are used by the legacy "NewForecast" handler which
+// predates the codes above
+const InvalidForecastModel = "INVALID_FORECAST_MODEL"
+const NoAreaKeys = "NO_AREA_KEYS"
+const InvalidAreaKeys = "INVALID_AREA_KEYS"
+const InvalidConsumerGroups = "INVALID_CONSUMER_GROUPS"
+const CalculationModuleSlow = "CALCULATION_MODULE_SLOW"
 
 var titles = map[string]string{
 	MissingAuthorizationInformation: "Unauthorized",
@@ -22,6 +34,15 @@ var titles = map[string]string{
 	MissingShapeKeys:                "Missing Shape Keys",
 	InvalidShapeKey:                 "Invalid Shape Key",
 	InvalidConsumerGroup:            "Invalid Consumer Group",
+	InvalidForecastedYears:          "Invalid Forecasted Years",
+	InvalidHistoricalYears:          "Invalid Historical Years",
+	InvalidConfidenceLevel:          "Invalid Confidence Level",
+	InvalidForecastJobBody:          "Invalid Forecast Job Body",
+	InvalidForecastModel:            "Invalid Forecast Model",
+	NoAreaKeys:                      "No Area Keys",
+	InvalidAreaKeys:                 "Invalid Area Keys",
+	InvalidConsumerGroups:           "Invalid Consumer Groups",
+	CalculationModuleSlow:           "Calculation Module Slow",
 }
 
 var descriptions = map[string]string{
@@ -34,6 +55,15 @@ var descriptions = map[string]string{
 	MissingShapeKeys:          "The request does not contain a shape key",
 	InvalidShapeKey:           "One of the shape keys you provided is not valid. Please check your request",
 	InvalidConsumerGroup:      "One of the consumer groups you provided is not valid. Please check your request",
+	InvalidForecastedYears:    "The requested forecast horizon is out of range. Please check your request",
+	InvalidHistoricalYears:    "The requested amount of historical years is out of range. Please check your request",
+	InvalidConfidenceLevel:    "The requested confidence level must be between 0 and 1, exclusive. Please check your request",
+	InvalidForecastJobBody:    "The request body must be a non-empty JSON array of forecast requests",
+	InvalidForecastModel:      "The requested forecast model is not supported by this module",
+	NoAreaKeys:                "The request does not contain any area keys",
+	InvalidAreaKeys:           "One of the area keys you provided is not valid. Please check your request",
+	InvalidConsumerGroups:     "One of the consumer groups you provided is not valid. Please check your request",
+	CalculationModuleSlow:     "The calculation module did not respond in time. Please try again later",
 }
 
 var httpCodes = map[string]int{
@@ -44,4 +74,13 @@ var httpCodes = map[string]int{
 	MissingShapeKeys:                http.StatusBadRequest,
 	InvalidShapeKey:                 http.StatusUnprocessableEntity,
 	InvalidConsumerGroup:            http.StatusUnprocessableEntity,
+	InvalidForecastedYears:          http.StatusUnprocessableEntity,
+	InvalidHistoricalYears:          http.StatusUnprocessableEntity,
+	InvalidConfidenceLevel:          http.StatusUnprocessableEntity,
+	InvalidForecastJobBody:          http.StatusBadRequest,
+	InvalidForecastModel:            http.StatusNotFound,
+	NoAreaKeys:                      http.StatusBadRequest,
+	InvalidAreaKeys:                 http.StatusUnprocessableEntity,
+	InvalidConsumerGroups:           http.StatusUnprocessableEntity,
+	CalculationModuleSlow:           http.StatusGatewayTimeout,
 }
@@ -0,0 +1,65 @@
+package requestErrors
+
+import (
+	"fmt"
+
+	wisdomType "github.com/wisdom-oss/commonTypes"
+	"net/http"
+)
+
+// BuildRequestError builds a WISdoMError from one of the error codes defined
+// in this package. It returns an error if the code is not known to the
+// package
+func BuildRequestError(code string) (wisdomType.WISdoMError, error) {
+	title, titleSet := titles[code]
+	description, descriptionSet := descriptions[code]
+	httpCode, httpCodeSet := httpCodes[code]
+	if !titleSet || !descriptionSet || !httpCodeSet {
+		return wisdomType.WISdoMError{}, fmt.Errorf("unknown request error code: %s", code)
+	}
+	requestError := wisdomType.WISdoMError{
+		ErrorCode:        code,
+		ErrorTitle:       title,
+		ErrorDescription: description,
+		HttpStatusCode:   httpCode,
+	}
+	requestError.InferHttpStatusText()
+	return requestError, nil
+}
+
+// RespondWithRequestError sends the supplied WISdoMError to the request
+// origin
+func RespondWithRequestError(requestError wisdomType.WISdoMError, w http.ResponseWriter) {
+	_ = requestError.Send(w)
+}
+
+// RespondWithInternalError wraps the supplied native error into a
+// WISdoMError using the InternalError code and sends it to the request
+// origin
+func RespondWithInternalError(err error, w http.ResponseWriter) {
+	requestError := wisdomType.WISdoMError{}
+	requestError.WrapError(err)
+	requestError.ErrorCode = InternalError
+	_ = requestError.Send(w)
+}
+
+// GetRequestError is an alias for BuildRequestError kept for the legacy
+// "NewForecast" handler
+func GetRequestError(code string) (wisdomType.WISdoMError, error) {
+	return BuildRequestError(code)
+}
+
+// WrapInternalError wraps the supplied native error into a WISdoMError using
+// the InternalError code. It is kept for the legacy "NewForecast" handler
+func WrapInternalError(err error) (wisdomType.WISdoMError, error) {
+	requestError := wisdomType.WISdoMError{}
+	requestError.WrapError(err)
+	requestError.ErrorCode = InternalError
+	return requestError, nil
+}
+
+// SendError is an alias for RespondWithRequestError kept for the legacy
+// "NewForecast" handler
+func SendError(requestError wisdomType.WISdoMError, w http.ResponseWriter) {
+	RespondWithRequestError(requestError, w)
+}
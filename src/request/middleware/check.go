@@ -0,0 +1,72 @@
+package wisdomMiddleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	requestErrors "microservice/request/error"
+	"microservice/vars"
+)
+
+// AuthorizationCheck verifies that the request carries the
+// X-Authenticated-User and X-Authenticated-Groups headers injected by the
+// upstream Kong gateway. Requests missing this information are rejected
+// before they reach any route handler
+func AuthorizationCheck(nextHandler http.Handler) http.Handler {
+	logger := log.With().Str("wisdomMiddleware", "AuthorizationCheck").Logger()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := r.Header.Get("X-Authenticated-User")
+		groups := r.Header.Get("X-Authenticated-Groups")
+		if strings.TrimSpace(username) == "" || strings.TrimSpace(groups) == "" {
+			logger.Warn().Msg("rejecting request without authorization information")
+			requestError, err := requestErrors.BuildRequestError(requestErrors.MissingAuthorizationInformation)
+			if err != nil {
+				requestErrors.RespondWithInternalError(err, w)
+				return
+			}
+			requestErrors.RespondWithRequestError(requestError, w)
+			return
+		}
+		nextHandler.ServeHTTP(w, r)
+	})
+}
+
+// AdditionalResponseHeaders sets response headers which are sent on every
+// response produced by the microservice
+func AdditionalResponseHeaders(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Service", "water-usage-forecasts-rest")
+		nextHandler.ServeHTTP(w, r)
+	})
+}
+
+// TrackActiveForecastRequests increments vars.ActiveForecastRequests while a
+// ForecastRequest invocation is in flight so a graceful shutdown can wait for
+// it to finish before closing the AMQP connection. It must only be mounted on
+// the forecast route, not on every route served by the microservice
+func TrackActiveForecastRequests(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars.ActiveForecastRequests.Add(1)
+		defer vars.ActiveForecastRequests.Done()
+		nextHandler.ServeHTTP(w, r)
+	})
+}
+
+// ParseQueryParametersToContext extracts the repeatable "key" and
+// "consumerGroup" query parameters and stores them in the request context so
+// the route handlers do not have to parse the raw query themselves
+func ParseQueryParametersToContext(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+		if keys, set := query["key"]; set {
+			ctx = context.WithValue(ctx, "key", keys)
+		}
+		if consumerGroups, set := query["consumerGroup"]; set {
+			ctx = context.WithValue(ctx, "consumerGroup", consumerGroups)
+		}
+		nextHandler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/lib/pq"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"microservice/enums"
+	"microservice/replyrouter"
 	requestErrors "microservice/request/error"
+	"microservice/resultcache"
 	"microservice/structs"
 	"microservice/vars/globals"
 	"microservice/vars/globals/connections"
@@ -180,11 +182,33 @@ func NewForecast(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// check the result cache before dispatching the calculation to the
+	// calculation module, avoiding the up-to-240s AMQP wait for a request
+	// which was already answered since the usage data last changed
+	var resultCacheKey string
+	if dataVersion, dataVersionErr := resultcache.DataVersion(r.Context()); dataVersionErr != nil {
+		l.Warn().Err(dataVersionErr).Msg("unable to determine the usage data version, skipping the result cache")
+	} else if key, keyErr := resultcache.Key(forecastRequest, dataVersion); keyErr != nil {
+		l.Warn().Err(keyErr).Msg("unable to compute the result cache key, skipping the result cache")
+	} else {
+		resultCacheKey = key
+		if cached, hit := resultcache.Instance().Get(r.Context(), resultCacheKey); hit {
+			l.Info().Msg("serving forecast from the result cache")
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Cache", "HIT")
+			w.Write(cached)
+			return
+		}
+	}
+
 	// create a new context containing a timeout of 240 seconds
 	amqpCtx, cancel := context.WithTimeout(r.Context(), 240*time.Second)
 	defer cancel()
 
 	correlationId := middleware.GetReqID(r.Context())
+	deliveries := replyrouter.Register(correlationId)
+	defer replyrouter.Unregister(correlationId)
+
 	var timeMessagePublished time.Time
 	err := connections.AMQP.Channel.PublishWithContext(amqpCtx,
 		globals.Environment["AMQP_EXCHANGE"], globals.Environment["CALCULATION_MODULE_ROUTING_KEY"], false, false,
@@ -204,8 +228,6 @@ func NewForecast(w http.ResponseWriter, r *http.Request) {
 		l.Info().Msg("waiting for the modules response")
 		timeMessagePublished = time.Now()
 	}
-	ch := make(chan []byte)
-	go getAMQPResponse(ch, correlationId)
 
 	for {
 		select {
@@ -218,8 +240,12 @@ func NewForecast(w http.ResponseWriter, r *http.Request) {
 			}
 			requestErrors.SendError(e, w)
 			return
-		case forecast := <-ch:
+		case delivery := <-deliveries:
+			forecast := delivery.Body
 			elapsedTime := time.Since(timeMessagePublished)
+			if resultCacheKey != "" {
+				resultcache.Instance().Put(r.Context(), resultCacheKey, forecast)
+			}
 			w.Header().Set("Content-Type", "application/json")
 			w.Header().Set("X-Calculation-Time", fmt.Sprintf("%f", elapsedTime.Seconds()))
 			w.Write(forecast)
@@ -228,15 +254,3 @@ func NewForecast(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
-
-func getAMQPResponse(ch chan []byte, correlationId string) {
-	for {
-		for r := range connections.AMQP.Messages {
-			if correlationId == r.CorrelationId {
-				ch <- r.Body
-				return
-			}
-		}
-	}
-
-}
@@ -3,13 +3,35 @@
 package routes
 
 import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/lib/pq"
+
+	"microservice/cache"
 	"microservice/enums"
+	"microservice/forecast"
+	"microservice/metrics"
 	requestErrors "microservice/request/error"
+	"microservice/resultcache"
+	"microservice/structs"
+	"microservice/tasks"
 	"microservice/utils"
 	"microservice/vars"
-	"net/http"
+)
+
+// bounds for the optional forecastedYears/historicalYears query parameters
+// accepted by ForecastRequest. confidenceLevel is checked inline against the
+// open interval (0, 1)
+const (
+	defaultForecastedYears = 20
+	minForecastedYears     = 1
+	maxForecastedYears     = 50
+
+	minHistoricalYears = 1
+	maxHistoricalYears = 100
 )
 
 // ForecastRequest accepts a new request for a forecast and sends it to the calculation module
@@ -20,9 +42,17 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 	// check if the service supports the prognosis mode set in the path
 	requestedForecastMethod := enums.ForecastModel(chi.URLParam(request, "forecastMethod"))
 
+	// status is reported to forecast_requests_total once the handler returns
+	status := "error"
+	defer func() {
+		metrics.ForecastRequestsTotal.WithLabelValues(string(requestedForecastMethod), status).Inc()
+	}()
+
 	if requestedForecastMethod != enums.LinearForecast &&
 		requestedForecastMethod != enums.PolynomialForecast &&
-		requestedForecastMethod != enums.LogarithmicForecast {
+		requestedForecastMethod != enums.LogarithmicForecast &&
+		requestedForecastMethod != enums.ExponentialSmoothingForecast {
+		status = "rejected"
 		// since the method set is not supported, send back an error
 		requestError, err := requestErrors.BuildRequestError(requestErrors.UnsupportedForecastMethod)
 		if err != nil {
@@ -38,6 +68,7 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 
 	// now check if the keys are even set
 	if ctxRequestedMunicipalityKeys == nil {
+		status = "rejected"
 		// since the request did not contain any municipality keys, send back an error
 		requestError, err := requestErrors.BuildRequestError(requestErrors.MissingShapeKeys)
 		if err != nil {
@@ -52,8 +83,10 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 	requestedMunicipalityKeys := ctxRequestedMunicipalityKeys.([]string)
 
 	// now check if the municipality keys exist
+	stopQueryTimer := metrics.TimeQuery("check-municipality-keys")
 	knownMunicipalityKeyRows, queryError := vars.SqlQueries.Query(vars.PostgresConnection, "check-municipality-keys",
 		pq.Array(requestedMunicipalityKeys))
+	stopQueryTimer()
 	if queryError != nil {
 		requestErrors.RespondWithInternalError(queryError, responseWriter)
 		return
@@ -76,6 +109,7 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 	// now check if every requested municipality key is in the just collected known municipality keys
 	for _, requestedMunicipalityKey := range requestedMunicipalityKeys {
 		if !utils.ArrayContains(knownMunicipalityKeys, requestedMunicipalityKey) {
+			status = "rejected"
 			// since the key was not found in the database, the request is rejected
 			requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidShapeKey)
 			if err != nil {
@@ -87,6 +121,8 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 		}
 	}
 
+	metrics.ForecastShapeKeys.WithLabelValues(string(requestedForecastMethod)).Observe(float64(len(requestedMunicipalityKeys)))
+
 	// since all municipality keys passed the check, the consumer groups will now be checked if they are supplied
 	ctxRequestedConsumerGroups := requestContext.Value("consumerGroup")
 
@@ -95,7 +131,9 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 	if ctxRequestedConsumerGroups == nil {
 		vars.HttpLogger.Warn().Msg("no consumer group filter set. prognosis calculation may take more time")
 		// since the request did not contain any consumer groups, every consumer group will be used
+		stopQueryTimer := metrics.TimeQuery("get-consumer-groups")
 		consumerGroupRows, queryError := vars.SqlQueries.Query(vars.PostgresConnection, "get-consumer-groups")
+		stopQueryTimer()
 		if queryError != nil {
 			requestErrors.RespondWithInternalError(queryError, responseWriter)
 			return
@@ -115,8 +153,10 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 	} else {
 		// convert the requested consumer groups into a string array
 		requestedConsumerGroups = ctxRequestedConsumerGroups.([]string)
+		stopQueryTimer := metrics.TimeQuery("check-consumer-groups")
 		knownConsumerGroupRows, queryError := vars.SqlQueries.Query(vars.PostgresConnection, "check-consumer-groups",
 			pq.Array(requestedConsumerGroups))
+		stopQueryTimer()
 		if queryError != nil {
 			requestErrors.RespondWithInternalError(queryError, responseWriter)
 			return
@@ -137,6 +177,7 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 		// now check if every requested consumer group is found in the database
 		for _, requestedConsumerGroup := range requestedConsumerGroups {
 			if !utils.ArrayContains(knownConsumerGroups, requestedConsumerGroup) {
+				status = "rejected"
 				// since the consumer group was not found in the database, the request is rejected
 				requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidConsumerGroup)
 				if err != nil {
@@ -149,4 +190,145 @@ func ForecastRequest(responseWriter http.ResponseWriter, request *http.Request)
 		}
 	}
 
+	// the forecast horizon, amount of historical data and confidence level
+	// can be overridden via query parameters, each validated against a
+	// sensible range before being passed on to the calculation module
+	forecastedYears := defaultForecastedYears
+	if rawForecastedYears := request.URL.Query().Get("forecastedYears"); rawForecastedYears != "" {
+		parsedForecastedYears, parseErr := strconv.Atoi(rawForecastedYears)
+		if parseErr != nil || parsedForecastedYears < minForecastedYears || parsedForecastedYears > maxForecastedYears {
+			status = "rejected"
+			requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidForecastedYears)
+			if err != nil {
+				requestErrors.RespondWithInternalError(err, responseWriter)
+				return
+			}
+			requestErrors.RespondWithRequestError(requestError, responseWriter)
+			return
+		}
+		forecastedYears = parsedForecastedYears
+	}
+
+	var historicalYears int
+	if rawHistoricalYears := request.URL.Query().Get("historicalYears"); rawHistoricalYears != "" {
+		parsedHistoricalYears, parseErr := strconv.Atoi(rawHistoricalYears)
+		if parseErr != nil || parsedHistoricalYears < minHistoricalYears || parsedHistoricalYears > maxHistoricalYears {
+			status = "rejected"
+			requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidHistoricalYears)
+			if err != nil {
+				requestErrors.RespondWithInternalError(err, responseWriter)
+				return
+			}
+			requestErrors.RespondWithRequestError(requestError, responseWriter)
+			return
+		}
+		historicalYears = parsedHistoricalYears
+	}
+
+	var confidenceLevel *float64
+	if rawConfidenceLevel := request.URL.Query().Get("confidenceLevel"); rawConfidenceLevel != "" {
+		parsedConfidenceLevel, parseErr := strconv.ParseFloat(rawConfidenceLevel, 64)
+		if parseErr != nil || parsedConfidenceLevel <= 0 || parsedConfidenceLevel >= 1 {
+			status = "rejected"
+			requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidConfidenceLevel)
+			if err != nil {
+				requestErrors.RespondWithInternalError(err, responseWriter)
+				return
+			}
+			requestErrors.RespondWithRequestError(requestError, responseWriter)
+			return
+		}
+		confidenceLevel = &parsedConfidenceLevel
+	}
+
+	// since all input validation passed, build the calculation request which
+	// is either served from the cache or dispatched to the calculation module
+	forecastRequest := structs.CalculationRequest{
+		Model:           requestedForecastMethod,
+		Keys:            requestedMunicipalityKeys,
+		ConsumerGroups:  requestedConsumerGroups,
+		ForecastedYears: forecastedYears,
+		HistoricalYears: historicalYears,
+		ConfidenceLevel: confidenceLevel,
+	}
+
+	cacheKey, err := cache.Key(forecastRequest)
+	if err != nil {
+		requestErrors.RespondWithInternalError(err, responseWriter)
+		return
+	}
+
+	// check the fast in-memory/Redis result cache before falling back to the
+	// slower object storage cache below, avoiding a network round trip for a
+	// request which was already answered since the usage data last changed
+	var resultCacheKey string
+	if dataVersion, dataVersionErr := resultcache.DataVersion(requestContext); dataVersionErr != nil {
+		vars.HttpLogger.Warn().Err(dataVersionErr).Msg("unable to determine the usage data version, skipping the result cache")
+	} else if key, keyErr := resultcache.Key(forecastRequest, dataVersion); keyErr != nil {
+		vars.HttpLogger.Warn().Err(keyErr).Msg("unable to compute the result cache key, skipping the result cache")
+	} else {
+		resultCacheKey = key
+		if cached, hit := resultcache.Instance().Get(requestContext, resultCacheKey); hit {
+			status = "cache_hit"
+			responseWriter.Header().Set("Content-Type", "application/json")
+			responseWriter.Header().Set("X-Cache", "HIT")
+			responseWriter.Write(cached)
+			return
+		}
+	}
+
+	if cachedResult, hit, cacheErr := cache.Get(requestContext, vars.ObjectStorage, vars.ForecastResultBucket, cacheKey); cacheErr == nil && hit {
+		status = "cache_hit"
+		vars.HttpLogger.Info().Str("cacheKey", cacheKey).Msg("serving forecast from cache")
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.Header().Set("X-Cache", "HIT")
+		responseWriter.Write(cachedResult)
+		return
+	}
+
+	// the holt-winters model is calculated in-process instead of being
+	// dispatched to the external calculation module, so it is served directly
+	// without going through the background task queue
+	if requestedForecastMethod == enums.ExponentialSmoothingForecast {
+		forecastResult, computeErr := forecast.Compute(forecastRequest)
+		if computeErr != nil {
+			requestErrors.RespondWithInternalError(computeErr, responseWriter)
+			return
+		}
+
+		resultJSON, marshalErr := json.Marshal(forecastResult)
+		if marshalErr != nil {
+			requestErrors.RespondWithInternalError(marshalErr, responseWriter)
+			return
+		}
+		if putErr := cache.Put(requestContext, vars.ObjectStorage, vars.ForecastResultBucket, cacheKey, string(requestedForecastMethod), resultJSON); putErr != nil {
+			vars.HttpLogger.Warn().Err(putErr).Str("cacheKey", cacheKey).Msg("unable to store forecast result in the object storage")
+		}
+		if resultCacheKey != "" {
+			resultcache.Instance().Put(requestContext, resultCacheKey, resultJSON)
+		}
+
+		status = "computed"
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.Write(resultJSON)
+		return
+	}
+
+	// since no cached result exists, enqueue the calculation as a background
+	// task instead of blocking the request until the calculation module
+	// responds
+	task, err := tasks.Enqueue(forecastRequest)
+	if err != nil {
+		requestErrors.RespondWithInternalError(err, responseWriter)
+		return
+	}
+
+	status = "enqueued"
+	responseWriter.Header().Set("Location", "/status/"+task.ID)
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(responseWriter).Encode(map[string]string{
+		"taskId": task.ID,
+		"status": "/status/" + task.ID,
+	})
 }
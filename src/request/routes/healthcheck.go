@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"microservice/vars"
+)
+
+// HealthCheck reports that the microservice process is up, regardless of
+// whether it is currently able to serve new requests. It is used for the
+// liveness probe
+func HealthCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "UP"})
+}
+
+// ReadinessCheck reports whether the microservice instance is ready to
+// receive new requests: it must not be draining, the database must be
+// reachable and the AMQP channel must still be open. It is used for the
+// readiness probe so the gateway stops routing to an instance that is
+// shutting down
+func ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	if vars.Draining.Load() {
+		respondNotReady(w, "instance is draining")
+		return
+	}
+	if err := vars.PostgresConnection.Ping(); err != nil {
+		respondNotReady(w, "database is not reachable")
+		return
+	}
+	if vars.AMQP.Channel == nil || vars.AMQP.Channel.IsClosed() {
+		respondNotReady(w, "amqp channel is not open")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "READY"})
+}
+
+func respondNotReady(w http.ResponseWriter, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "NOT_READY", "reason": reason})
+}
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"microservice/jobs"
+	requestErrors "microservice/request/error"
+)
+
+// CreateForecastJob accepts a batch of forecast requests, persists them and
+// dispatches one AMQP message per combination, returning the job manifest
+// immediately
+func CreateForecastJob(responseWriter http.ResponseWriter, request *http.Request) {
+	var subRequests []jobs.SubRequest
+	if err := json.NewDecoder(request.Body).Decode(&subRequests); err != nil {
+		requestError, buildErr := requestErrors.BuildRequestError(requestErrors.InvalidForecastJobBody)
+		if buildErr != nil {
+			requestErrors.RespondWithInternalError(buildErr, responseWriter)
+			return
+		}
+		requestErrors.RespondWithRequestError(requestError, responseWriter)
+		return
+	}
+
+	if len(subRequests) == 0 {
+		requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidForecastJobBody)
+		if err != nil {
+			requestErrors.RespondWithInternalError(err, responseWriter)
+			return
+		}
+		requestErrors.RespondWithRequestError(requestError, responseWriter)
+		return
+	}
+
+	// validate every sub-request against the database the same way
+	// ForecastRequest validates its query parameters, before publishing
+	// anything to the calculation module
+	for _, subRequest := range subRequests {
+		if err := jobs.Validate(subRequest); err != nil {
+			var code string
+			switch {
+			case errors.Is(err, jobs.ErrUnsupportedModel):
+				code = requestErrors.UnsupportedForecastMethod
+			case errors.Is(err, jobs.ErrMissingKeys):
+				code = requestErrors.MissingShapeKeys
+			case errors.Is(err, jobs.ErrInvalidKey):
+				code = requestErrors.InvalidShapeKey
+			case errors.Is(err, jobs.ErrInvalidConsumerGroup):
+				code = requestErrors.InvalidConsumerGroup
+			default:
+				requestErrors.RespondWithInternalError(err, responseWriter)
+				return
+			}
+
+			requestError, buildErr := requestErrors.BuildRequestError(code)
+			if buildErr != nil {
+				requestErrors.RespondWithInternalError(buildErr, responseWriter)
+				return
+			}
+			requestErrors.RespondWithRequestError(requestError, responseWriter)
+			return
+		}
+	}
+
+	job, err := jobs.Create(subRequests)
+	if err != nil {
+		requestErrors.RespondWithInternalError(err, responseWriter)
+		return
+	}
+
+	responseWriter.Header().Set("Location", "/forecasts/"+job.ID)
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(responseWriter).Encode(job)
+}
+
+// ForecastJobStatus reports the current state of a batch forecast job and
+// all of its sub-results
+func ForecastJobStatus(responseWriter http.ResponseWriter, request *http.Request) {
+	jobID := chi.URLParam(request, "jobID")
+
+	job, found, err := jobs.Get(jobID)
+	if err != nil {
+		requestErrors.RespondWithInternalError(err, responseWriter)
+		return
+	}
+	if !found {
+		http.NotFound(responseWriter, request)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(responseWriter).Encode(job)
+}
+
+// ForecastJobSubResult reports a single sub-result of a batch forecast job
+func ForecastJobSubResult(responseWriter http.ResponseWriter, request *http.Request) {
+	jobID := chi.URLParam(request, "jobID")
+	subID := chi.URLParam(request, "subID")
+
+	subResult, found, err := jobs.GetSubResult(jobID, subID)
+	if err != nil {
+		requestErrors.RespondWithInternalError(err, responseWriter)
+		return
+	}
+	if !found {
+		http.NotFound(responseWriter, request)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(responseWriter).Encode(subResult)
+}
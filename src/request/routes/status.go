@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	requestErrors "microservice/request/error"
+	"microservice/tasks"
+)
+
+// TaskStatus reports the current state of a background forecast task
+// enqueued by ForecastRequest, returning the result once it has succeeded
+func TaskStatus(responseWriter http.ResponseWriter, request *http.Request) {
+	taskID := chi.URLParam(request, "id")
+
+	task, found, err := tasks.Get(taskID)
+	if err != nil {
+		requestErrors.RespondWithInternalError(err, responseWriter)
+		return
+	}
+	if !found {
+		http.NotFound(responseWriter, request)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(responseWriter).Encode(task)
+}
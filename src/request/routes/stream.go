@@ -0,0 +1,175 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lib/pq"
+
+	"microservice/enums"
+	requestErrors "microservice/request/error"
+	"microservice/structs"
+	"microservice/transport"
+	"microservice/utils"
+	"microservice/vars"
+)
+
+// NewForecastStream is the Server-Sent Events variant of ForecastRequest. It
+// runs the same municipality-key/consumer-group validation, then dispatches
+// the calculation via the configured transport (see microservice/transport)
+// directly instead of going through the background task queue, forwarding
+// every Result the transport delivers - partial progress updates as well as
+// the final result - as an SSE frame until the final result is sent or the
+// client disconnects. With AMQPTransport only the first reply for the
+// correlation id is forwarded, as the final result; further messages for the
+// same correlation id are not forwarded
+func NewForecastStream(responseWriter http.ResponseWriter, request *http.Request) {
+	requestContext := request.Context()
+
+	requestedForecastMethod := enums.ForecastModel(chi.URLParam(request, "forecastMethod"))
+	if requestedForecastMethod != enums.LinearForecast &&
+		requestedForecastMethod != enums.PolynomialForecast &&
+		requestedForecastMethod != enums.LogarithmicForecast &&
+		requestedForecastMethod != enums.ExponentialSmoothingForecast {
+		requestError, err := requestErrors.BuildRequestError(requestErrors.UnsupportedForecastMethod)
+		if err != nil {
+			requestErrors.RespondWithInternalError(err, responseWriter)
+			return
+		}
+		requestErrors.RespondWithRequestError(requestError, responseWriter)
+		return
+	}
+
+	ctxRequestedMunicipalityKeys := requestContext.Value("key")
+	if ctxRequestedMunicipalityKeys == nil {
+		requestError, err := requestErrors.BuildRequestError(requestErrors.MissingShapeKeys)
+		if err != nil {
+			requestErrors.RespondWithInternalError(err, responseWriter)
+			return
+		}
+		requestErrors.RespondWithRequestError(requestError, responseWriter)
+		return
+	}
+	requestedMunicipalityKeys := ctxRequestedMunicipalityKeys.([]string)
+
+	knownMunicipalityKeyRows, queryError := vars.SqlQueries.Query(vars.PostgresConnection, "check-municipality-keys",
+		pq.Array(requestedMunicipalityKeys))
+	if queryError != nil {
+		requestErrors.RespondWithInternalError(queryError, responseWriter)
+		return
+	}
+	var knownMunicipalityKeys []string
+	for knownMunicipalityKeyRows.Next() {
+		var knownMunicipalityKey string
+		if scanErr := knownMunicipalityKeyRows.Scan(&knownMunicipalityKey); scanErr != nil {
+			requestErrors.RespondWithInternalError(scanErr, responseWriter)
+			return
+		}
+		knownMunicipalityKeys = append(knownMunicipalityKeys, knownMunicipalityKey)
+	}
+	for _, requestedMunicipalityKey := range requestedMunicipalityKeys {
+		if !utils.ArrayContains(knownMunicipalityKeys, requestedMunicipalityKey) {
+			requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidShapeKey)
+			if err != nil {
+				requestErrors.RespondWithInternalError(err, responseWriter)
+				return
+			}
+			requestErrors.RespondWithRequestError(requestError, responseWriter)
+			return
+		}
+	}
+
+	ctxRequestedConsumerGroups := requestContext.Value("consumerGroup")
+	var requestedConsumerGroups []string
+	if ctxRequestedConsumerGroups == nil {
+		consumerGroupRows, queryError := vars.SqlQueries.Query(vars.PostgresConnection, "get-consumer-groups")
+		if queryError != nil {
+			requestErrors.RespondWithInternalError(queryError, responseWriter)
+			return
+		}
+		for consumerGroupRows.Next() {
+			var consumerGroup string
+			if scanErr := consumerGroupRows.Scan(&consumerGroup); scanErr != nil {
+				requestErrors.RespondWithInternalError(scanErr, responseWriter)
+				return
+			}
+			requestedConsumerGroups = append(requestedConsumerGroups, consumerGroup)
+		}
+	} else {
+		requestedConsumerGroups = ctxRequestedConsumerGroups.([]string)
+		knownConsumerGroupRows, queryError := vars.SqlQueries.Query(vars.PostgresConnection, "check-consumer-groups",
+			pq.Array(requestedConsumerGroups))
+		if queryError != nil {
+			requestErrors.RespondWithInternalError(queryError, responseWriter)
+			return
+		}
+		var knownConsumerGroups []string
+		for knownConsumerGroupRows.Next() {
+			var knownConsumerGroup string
+			if scanErr := knownConsumerGroupRows.Scan(&knownConsumerGroup); scanErr != nil {
+				requestErrors.RespondWithInternalError(scanErr, responseWriter)
+				return
+			}
+			knownConsumerGroups = append(knownConsumerGroups, knownConsumerGroup)
+		}
+		for _, requestedConsumerGroup := range requestedConsumerGroups {
+			if !utils.ArrayContains(knownConsumerGroups, requestedConsumerGroup) {
+				requestError, err := requestErrors.BuildRequestError(requestErrors.InvalidConsumerGroup)
+				if err != nil {
+					requestErrors.RespondWithInternalError(err, responseWriter)
+					return
+				}
+				requestErrors.RespondWithRequestError(requestError, responseWriter)
+				return
+			}
+		}
+	}
+
+	flusher, flusherSupported := responseWriter.(http.Flusher)
+	if !flusherSupported {
+		requestErrors.RespondWithInternalError(fmt.Errorf("streaming is not supported for this request"), responseWriter)
+		return
+	}
+
+	forecastRequest := structs.CalculationRequest{
+		Model:           requestedForecastMethod,
+		Keys:            requestedMunicipalityKeys,
+		ConsumerGroups:  requestedConsumerGroups,
+		ForecastedYears: defaultForecastedYears,
+	}
+
+	correlationId := middleware.GetReqID(requestContext)
+	results, err := transport.Select().Submit(requestContext, forecastRequest, correlationId)
+	if err != nil {
+		requestErrors.RespondWithInternalError(err, responseWriter)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/event-stream")
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	responseWriter.Header().Set("Connection", "keep-alive")
+	responseWriter.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-requestContext.Done():
+			return
+		case result, open := <-results:
+			if !open {
+				return
+			}
+			eventType := "partial"
+			if !result.Partial {
+				eventType = "done"
+			}
+			_, _ = fmt.Fprintf(responseWriter, "event: %s\ndata: %s\n\n", eventType, result.Body)
+			flusher.Flush()
+			if !result.Partial {
+				return
+			}
+		}
+	}
+}
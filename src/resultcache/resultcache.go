@@ -0,0 +1,150 @@
+// Package resultcache implements a fast result cache checked in front of the
+// slower S3-compatible object storage cache (see microservice/cache) used by
+// ForecastRequest: an in-memory LRU cache backed by an optional Redis
+// instance, keyed by a fingerprint of the request plus the current data
+// version of the referenced usage tables so it invalidates automatically
+// whenever the underlying usage data changes
+package resultcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/redis/go-redis/v9"
+
+	"microservice/structs"
+	"microservice/vars"
+)
+
+// defaultLRUCapacity is used when RESULT_CACHE_LRU_SIZE is not set or cannot
+// be parsed as a positive integer
+const defaultLRUCapacity = 256
+
+// defaultTTL is how long a cached result is kept in the optional Redis
+// backend before it expires
+const defaultTTL = 15 * time.Minute
+
+// fingerprint is the canonical, hashable representation of a cached forecast
+// request. the data version is included so the cache is invalidated
+// automatically whenever the underlying usage data changes
+type fingerprint struct {
+	Model           string   `json:"model"`
+	Keys            []string `json:"keys"`
+	ConsumerGroups  []string `json:"consumerGroups"`
+	ForecastedYears int      `json:"forecastedYears"`
+	DataVersion     string   `json:"dataVersion"`
+}
+
+// Cache is an in-memory LRU cache optionally fronted by Redis so multiple
+// instances of the microservice share cached results
+type Cache struct {
+	memory *lru.Cache[string, []byte]
+	redis  *redis.Client
+	ttl    time.Duration
+}
+
+var (
+	instance     *Cache
+	instanceOnce sync.Once
+)
+
+// Instance returns the process-wide result cache, initializing it from the
+// environment on first use
+func Instance() *Cache {
+	instanceOnce.Do(func() {
+		instance = newCache()
+	})
+	return instance
+}
+
+func newCache() *Cache {
+	capacity, err := strconv.Atoi(vars.Environment["RESULT_CACHE_LRU_SIZE"])
+	if err != nil || capacity <= 0 {
+		capacity = defaultLRUCapacity
+	}
+	memoryCache, _ := lru.New[string, []byte](capacity)
+
+	cache := &Cache{memory: memoryCache, ttl: defaultTTL}
+
+	if redisAddress := vars.Environment["REDIS_ADDRESS"]; redisAddress != "" {
+		cache.redis = redis.NewClient(&redis.Options{
+			Addr:     redisAddress,
+			Password: vars.Environment["REDIS_PASSWORD"],
+		})
+	}
+
+	return cache
+}
+
+// Key computes the cache key for a forecast request, sorting keys and
+// consumer groups so that requests differing only in query parameter order
+// share the same entry
+func Key(request structs.CalculationRequest, dataVersion string) (string, error) {
+	keys := append([]string(nil), request.Keys...)
+	sort.Strings(keys)
+	consumerGroups := append([]string(nil), request.ConsumerGroups...)
+	sort.Strings(consumerGroups)
+
+	canonical, err := json.Marshal(fingerprint{
+		Model:           string(request.Model),
+		Keys:            keys,
+		ConsumerGroups:  consumerGroups,
+		ForecastedYears: request.ForecastedYears,
+		DataVersion:     dataVersion,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// DataVersion returns a token which changes whenever the usage tables
+// referenced by a forecast calculation are updated, e.g. max(updated_at)
+// over those tables
+func DataVersion(ctx context.Context) (string, error) {
+	row, err := vars.SqlQueries.QueryRow(vars.PostgresConnection, "get-usage-data-version")
+	if err != nil {
+		return "", err
+	}
+	var version string
+	if err := row.Scan(&version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// Get returns the cached response for key, checking the in-memory cache
+// first and falling back to Redis if it is configured
+func (cache *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	if value, hit := cache.memory.Get(key); hit {
+		return value, true
+	}
+
+	if cache.redis == nil {
+		return nil, false
+	}
+	value, err := cache.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	cache.memory.Add(key, value)
+	return value, true
+}
+
+// Put stores value under key in the in-memory cache and, if configured, in
+// Redis with the configured TTL
+func (cache *Cache) Put(ctx context.Context, key string, value []byte) {
+	cache.memory.Add(key, value)
+	if cache.redis != nil {
+		_ = cache.redis.Set(ctx, key, value, cache.ttl).Err()
+	}
+}
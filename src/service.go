@@ -3,12 +3,18 @@ package main
 import (
 	context2 "context"
 	"fmt"
+	"strconv"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httplog"
 	gateway "github.com/wisdom-oss/golang-kong-access"
+	"microservice/jobs"
+	"microservice/metrics"
 	middleware2 "microservice/request/middleware"
 	"microservice/request/routes"
+	"microservice/tasks"
+	"microservice/transport"
 	"microservice/utils"
 	"net/http"
 	"os"
@@ -20,6 +26,14 @@ import (
 	"microservice/vars"
 )
 
+// defaultTaskWorkerConcurrency is used when TASK_WORKER_CONCURRENCY is not
+// set or cannot be parsed as an integer
+const defaultTaskWorkerConcurrency = 4
+
+// defaultDrainTimeout is used when SHUTDOWN_DRAIN_TIMEOUT is not set or
+// cannot be parsed as a positive integer number of seconds
+const defaultDrainTimeout = 30 * time.Second
+
 /*
 This function is used to set up the http server for the microservice
 */
@@ -28,17 +42,66 @@ func main() {
 		JSON:     true,
 		LogLevel: "warn",
 	})
+
+	// connect to the object storage used to cache already calculated
+	// forecast results
+	if err := vars.ConnectObjectStorage(); err != nil {
+		log.WithError(err).Fatal("unable to connect to the object storage")
+	}
+
 	// Set up the routing of the different functions
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
 	router.Use(httplog.RequestLogger(vars.HttpLogger))
-	router.Use(middleware2.AuthorizationCheck)
+	router.Use(metrics.Middleware(func(r *http.Request) string {
+		return chi.RouteContext(r.Context()).RoutePattern()
+	}))
+	if vars.Environment["AUTH_MODE"] == "oidc" {
+		vars.AuthMode = "oidc"
+		router.Use(middleware2.OIDCAuthorizationCheck)
+	} else {
+		router.Use(middleware2.AuthorizationCheck)
+	}
 	router.Use(middleware2.AdditionalResponseHeaders)
 	router.Use(middleware2.ParseQueryParametersToContext)
-	router.HandleFunc("/{forecastMethod}", routes.ForecastRequest)
+	router.With(middleware2.TrackActiveForecastRequests).HandleFunc("/{forecastMethod}", routes.ForecastRequest)
+	router.With(middleware2.TrackActiveForecastRequests).Get("/{forecastMethod}/stream", routes.NewForecastStream)
+	router.HandleFunc("/status/{id}", routes.TaskStatus)
+	router.Post("/forecasts", routes.CreateForecastJob)
+	router.Get("/forecasts/{jobID}", routes.ForecastJobStatus)
+	router.Get("/forecasts/{jobID}/results/{subID}", routes.ForecastJobSubResult)
 	router.HandleFunc("/healthcheck", routes.HealthCheck)
+	router.HandleFunc("/healthcheck/live", routes.HealthCheck)
+	router.HandleFunc("/healthcheck/ready", routes.ReadinessCheck)
+	router.Handle("/metrics", metrics.Handler())
+
+	// backgroundContext is canceled during shutdown to stop both the task
+	// worker pool and the batch forecast job result consumer
+	backgroundContext, stopBackgroundWork := context2.WithCancel(context2.Background())
+	defer stopBackgroundWork()
+
+	// start the consumer which fans out calculation module replies to
+	// whichever forecast task is waiting for them, by correlation id
+	if err := transport.StartReplyConsumer(backgroundContext); err != nil {
+		log.WithError(err).Fatal("unable to start the forecast calculation reply consumer")
+	}
+
+	// start the worker pool which dispatches enqueued forecast tasks to the
+	// calculation module
+	concurrency, err := strconv.Atoi(vars.Environment["TASK_WORKER_CONCURRENCY"])
+	if err != nil || concurrency <= 0 {
+		concurrency = defaultTaskWorkerConcurrency
+	}
+	tasks.StartWorkers(backgroundContext, concurrency)
+
+	// start the background consumer which matches batch forecast job replies
+	// back to their sub-result by correlation id, independent of any single
+	// HTTP request
+	if err := jobs.StartResultConsumer(backgroundContext); err != nil {
+		log.WithError(err).Fatal("unable to start the batch forecast job result consumer")
+	}
 
 	// Configure the HTTP server
 	server := &http.Server{
@@ -65,33 +128,62 @@ func main() {
 	<-cancelSignal
 
 	log.Info("Shutting down the microservice...")
+	vars.Draining.Store(true)
+
+	drainTimeout := defaultDrainTimeout
+	if seconds, parseErr := strconv.Atoi(vars.Environment["SHUTDOWN_DRAIN_TIMEOUT"]); parseErr == nil && seconds > 0 {
+		drainTimeout = time.Duration(seconds) * time.Second
+	}
+	drainContext, cancelDrain := context2.WithTimeout(context2.Background(), drainTimeout)
+	defer cancelDrain()
+
+	// stop accepting new connections first so the readiness check above has
+	// already started failing before any in-flight work is interrupted
+	if err := server.Shutdown(drainContext); err != nil {
+		log.WithError(err).Error("An error occurred while stopping the http server")
+	}
+
+	// wait for every in-flight forecast request to finish, or for the drain
+	// timeout to elapse, before tearing down the AMQP connection they rely on
+	requestsDrained := make(chan struct{})
+	go func() {
+		vars.ActiveForecastRequests.Wait()
+		close(requestsDrained)
+	}()
+	select {
+	case <-requestsDrained:
+		log.Info("all in-flight forecast requests finished")
+	case <-drainContext.Done():
+		log.Warn("drain timeout elapsed while forecast requests were still in flight")
+	}
+
+	log.Info("stopping the forecast task workers and the batch job result consumer")
+	stopBackgroundWork()
+
+	if vars.AMQP.Channel != nil {
+		if err := vars.AMQP.Channel.Close(); err != nil {
+			log.WithError(err).Error("An error occurred while closing the amqp channel")
+		}
+	}
+	if vars.AMQP.Connection != nil {
+		if err := vars.AMQP.Connection.Close(); err != nil {
+			log.WithError(err).Error("An error occurred while closing the amqp connection")
+		}
+	}
 
 	log.Info("Closing the database connection")
-	dbCloseErr := vars.PostgresConnection.Close()
-	if dbCloseErr != nil {
-		log.WithError(dbCloseErr).Fatal("An error occurred while closing the connection to the database")
+	if dbCloseErr := vars.PostgresConnection.Close(); dbCloseErr != nil {
+		log.WithError(dbCloseErr).Error("An error occurred while closing the connection to the database")
 	}
+
 	localIPAddress, _ := utils.LocalIPv4Address()
 	targetAddress := fmt.Sprintf("%s:%d", localIPAddress, vars.ListenPort)
 
 	success, err := gateway.DeleteUpstreamTarget(targetAddress, vars.ServiceName)
 	if err != nil {
-		log.WithError(err).Fatal("unable to deregister the service instance")
+		log.WithError(err).Error("unable to deregister the service instance")
 	}
-
 	if success {
 		log.Info("deleted target from the upstream")
 	}
-
-	context, cancel := context2.WithTimeout(context2.Background(), time.Second*15)
-	defer cancel()
-
-	go func() {
-
-		err = server.Shutdown(context)
-		if err != nil {
-			log.WithError(err).Fatal("An error occurred while stopping the http server")
-		}
-	}()
-
 }
@@ -19,4 +19,6 @@ type CalculationRequest struct {
 	Keys            []string            `json:"keys"`
 	ConsumerGroups  []string            `json:"consumerGroups"`
 	ForecastedYears int                 `json:"forecastSize"`
+	HistoricalYears int                 `json:"historicalYears,omitempty"`
+	ConfidenceLevel *float64            `json:"confidenceLevel,omitempty"`
 }
@@ -0,0 +1,22 @@
+package structs
+
+import "microservice/enums"
+
+// This file contains the structs used to shape the forecast result returned
+// to the client, regardless of whether it was calculated by the external
+// calculation module or the in-process fallback in microservice/forecast.
+
+// ForecastResult is the JSON body returned for a completed forecast
+type ForecastResult struct {
+	Model  enums.ForecastModel `json:"model"`
+	Series []ForecastSeries    `json:"series"`
+}
+
+// ForecastSeries holds the forecasted values for a single municipality/
+// consumer group combination
+type ForecastSeries struct {
+	Key           string    `json:"key"`
+	ConsumerGroup string    `json:"consumerGroup"`
+	Values        []float64 `json:"values"`
+	Warning       string    `json:"warning,omitempty"`
+}
@@ -0,0 +1,272 @@
+// Package tasks implements a small background task subsystem for
+// dispatching forecast calculations asynchronously. It is modeled after
+// github.com/hibiken/asynq: tasks are enqueued under a type name, handed to
+// a pool of worker goroutines and their state is tracked so that a client
+// can poll for the result instead of blocking on the HTTP connection
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"microservice/cache"
+	"microservice/metrics"
+	"microservice/resultcache"
+	"microservice/structs"
+	"microservice/transport"
+	"microservice/vars"
+)
+
+// ForecastCalculate is the task type used to dispatch a forecast
+// calculation to the AMQP calculation module
+const ForecastCalculate = "forecast:calculate"
+
+// task state values stored in the forecast_tasks table
+const (
+	StatePending   = "pending"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+)
+
+// Task represents a single enqueued forecast calculation and its current
+// state
+type Task struct {
+	ID         string                     `json:"id"`
+	State      string                     `json:"state"`
+	Payload    structs.CalculationRequest `json:"payload"`
+	Result     json.RawMessage            `json:"result,omitempty"`
+	Error      string                     `json:"error,omitempty"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+	FinishedAt *time.Time                 `json:"finishedAt,omitempty"`
+}
+
+// queue is the in-process hand-off between Enqueue and the worker pool
+// started with StartWorkers. it is buffered so that enqueuing a task does not
+// block the request that created it, unless 256 task ids are already waiting
+// for a worker
+var queue = make(chan string, 256)
+
+// cacheKeys remembers the object storage cache key a task was enqueued with
+// so the worker pool can populate the cache once the result is in, without
+// having the caller thread it through the database
+var (
+	cacheKeysMutex sync.Mutex
+	cacheKeys      = make(map[string]string)
+)
+
+// resultCacheKeys mirrors cacheKeys, but for the faster in-memory/Redis
+// result cache (see microservice/resultcache) instead of the object storage
+// cache
+var (
+	resultCacheKeysMutex sync.Mutex
+	resultCacheKeys      = make(map[string]string)
+)
+
+// Enqueue persists a new task in the `pending` state and hands its id to
+// the worker pool for processing
+func Enqueue(payload structs.CalculationRequest) (Task, error) {
+	task := Task{
+		ID:        uuid.NewString(),
+		State:     StatePending,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+
+	payloadJSON, err := json.Marshal(task.Payload)
+	if err != nil {
+		return Task{}, err
+	}
+
+	_, err = vars.SqlQueries.Exec(vars.PostgresConnection, "insert-forecast-task",
+		task.ID, task.State, payloadJSON, task.CreatedAt)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if cacheKey, keyErr := cache.Key(payload); keyErr == nil {
+		cacheKeysMutex.Lock()
+		cacheKeys[task.ID] = cacheKey
+		cacheKeysMutex.Unlock()
+	}
+
+	if dataVersion, dataVersionErr := resultcache.DataVersion(context.Background()); dataVersionErr == nil {
+		if resultCacheKey, keyErr := resultcache.Key(payload, dataVersion); keyErr == nil {
+			resultCacheKeysMutex.Lock()
+			resultCacheKeys[task.ID] = resultCacheKey
+			resultCacheKeysMutex.Unlock()
+		}
+	}
+
+	queue <- task.ID
+	return task, nil
+}
+
+// Get loads the current state of a task by its id
+func Get(id string) (Task, bool, error) {
+	row, err := vars.SqlQueries.QueryRow(vars.PostgresConnection, "get-forecast-task", id)
+	if err != nil {
+		return Task{}, false, err
+	}
+
+	var task Task
+	var payloadJSON, resultJSON []byte
+	var finishedAt sql.NullTime
+	scanErr := row.Scan(&task.ID, &task.State, &payloadJSON, &resultJSON, &task.Error, &task.CreatedAt, &finishedAt)
+	if scanErr != nil {
+		return Task{}, false, nil
+	}
+
+	if unmarshalErr := json.Unmarshal(payloadJSON, &task.Payload); unmarshalErr != nil {
+		return Task{}, false, unmarshalErr
+	}
+	if len(resultJSON) > 0 {
+		task.Result = resultJSON
+	}
+	if finishedAt.Valid {
+		task.FinishedAt = &finishedAt.Time
+	}
+
+	return task, true, nil
+}
+
+// StartWorkers starts concurrency worker goroutines which consume enqueued
+// task ids, dispatch them to the calculation module via the configured
+// transport and persist the result. It should be called once from main after the AMQP connection has
+// been established
+func StartWorkers(ctx context.Context, concurrency int) {
+	for i := 0; i < concurrency; i++ {
+		go worker(ctx)
+	}
+}
+
+func worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-queue:
+			runTask(id)
+		}
+	}
+}
+
+// runTask drives a single task through the calculation module round-trip,
+// retrying the submission with exponential backoff before giving up and
+// marking the task as failed
+func runTask(id string) {
+	task, found, err := Get(id)
+	if err != nil || !found {
+		vars.HttpLogger.Error().Err(err).Str("taskId", id).Msg("unable to load task for processing")
+		return
+	}
+
+	setState(task.ID, StateRunning)
+
+	result, err := dispatch(task)
+	if err != nil {
+		vars.HttpLogger.Error().Err(err).Str("taskId", id).Msg("forecast task failed")
+		fail(task.ID, err)
+		return
+	}
+
+	succeed(task.ID, string(task.Payload.Model), result)
+}
+
+// dispatch submits the task's payload to the calculation module through the
+// configured transport (see microservice/transport) and waits for the
+// reply, retrying the submission itself with exponential backoff on
+// transport failures
+func dispatch(task Task) ([]byte, error) {
+	dispatchStart := time.Now()
+	defer func() {
+		metrics.ForecastDurationSeconds.WithLabelValues(string(task.Payload.Model)).Observe(time.Since(dispatchStart).Seconds())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	const maxAttempts = 5
+	backoff := time.Second
+	var results <-chan transport.Result
+	var submitErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		results, submitErr = transport.Select().Submit(ctx, task.Payload, task.ID)
+		if submitErr == nil {
+			break
+		}
+		metrics.AMQPPublishFailuresTotal.Inc()
+		vars.HttpLogger.Warn().Err(submitErr).Str("taskId", task.ID).Int("attempt", attempt+1).
+			Msg("unable to dispatch forecast task, retrying")
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if submitErr != nil {
+		return nil, fmt.Errorf("unable to dispatch forecast task after %d attempts: %w", maxAttempts, submitErr)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("calculation module did not respond in time")
+	case result, open := <-results:
+		if !open {
+			return nil, fmt.Errorf("calculation module did not respond in time")
+		}
+		return result.Body, nil
+	}
+}
+
+func setState(id string, state string) {
+	_, err := vars.SqlQueries.Exec(vars.PostgresConnection, "update-forecast-task-state", state, id)
+	if err != nil {
+		vars.HttpLogger.Error().Err(err).Str("taskId", id).Msg("unable to update task state")
+	}
+}
+
+func succeed(id string, model string, result []byte) {
+	_, err := vars.SqlQueries.Exec(vars.PostgresConnection, "finish-forecast-task", StateSucceeded, result, "", time.Now(), id)
+	if err != nil {
+		vars.HttpLogger.Error().Err(err).Str("taskId", id).Msg("unable to persist task result")
+	}
+
+	cacheKeysMutex.Lock()
+	cacheKey, hasCacheKey := cacheKeys[id]
+	delete(cacheKeys, id)
+	cacheKeysMutex.Unlock()
+
+	if hasCacheKey {
+		if putErr := cache.Put(context.Background(), vars.ObjectStorage, vars.ForecastResultBucket, cacheKey, model, result); putErr != nil {
+			vars.HttpLogger.Warn().Err(putErr).Str("taskId", id).Msg("unable to store forecast result in the object storage")
+		}
+	}
+
+	resultCacheKeysMutex.Lock()
+	resultCacheKey, hasResultCacheKey := resultCacheKeys[id]
+	delete(resultCacheKeys, id)
+	resultCacheKeysMutex.Unlock()
+
+	if hasResultCacheKey {
+		resultcache.Instance().Put(context.Background(), resultCacheKey, result)
+	}
+}
+
+func fail(id string, taskErr error) {
+	_, err := vars.SqlQueries.Exec(vars.PostgresConnection, "finish-forecast-task", StateFailed, nil, taskErr.Error(), time.Now(), id)
+	if err != nil {
+		vars.HttpLogger.Error().Err(err).Str("taskId", id).Msg("unable to persist task failure")
+	}
+
+	cacheKeysMutex.Lock()
+	delete(cacheKeys, id)
+	cacheKeysMutex.Unlock()
+
+	resultCacheKeysMutex.Lock()
+	delete(resultCacheKeys, id)
+	resultCacheKeysMutex.Unlock()
+}
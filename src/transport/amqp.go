@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"microservice/replyrouter"
+	"microservice/structs"
+	"microservice/vars"
+)
+
+// replyQueueName is the single, durable reply queue shared by every request
+// dispatched via AMQPTransport. It replaces the previous pattern of
+// declaring (and tearing down) an exclusive queue per request, avoiding a
+// QueueDeclare/Consume/QueueDelete round trip for every forecast calculation
+const replyQueueName = "water-usage-forecasts.calculation-replies"
+
+// AMQPTransport dispatches calculation requests over the message broker,
+// registering the request's correlation id with the shared reply router
+// (see StartReplyConsumer) instead of declaring and tearing down an
+// exclusive queue for every request. This is the original transport used by
+// tasks.dispatch and remains the default when CALCULATION_TRANSPORT is unset
+type AMQPTransport struct{}
+
+// Submit publishes request to the calculation module exchange and returns a
+// channel that receives the single matching response. ctx should carry the
+// timeout the caller is willing to wait for a response; once it expires the
+// channel is closed without a Result being sent
+func (t *AMQPTransport) Submit(ctx context.Context, request structs.CalculationRequest, correlationId string) (<-chan Result, error) {
+	message, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := replyrouter.Register(correlationId)
+
+	err = vars.AMQP.Channel.PublishWithContext(ctx,
+		vars.Environment["AMQP_EXCHANGE"], vars.Environment["CALCULATION_MODULE_ROUTING_KEY"], false, false,
+		amqp.Publishing{
+			ContentType:   "application/json",
+			Body:          message,
+			CorrelationId: correlationId,
+			ReplyTo:       replyQueueName,
+		})
+	if err != nil {
+		replyrouter.Unregister(correlationId)
+		return nil, err
+	}
+
+	results := make(chan Result, 1)
+	go func() {
+		defer close(results)
+		defer replyrouter.Unregister(correlationId)
+
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-reply:
+			results <- Result{Body: delivery.Body}
+		}
+	}()
+
+	return results, nil
+}
+
+// StartReplyConsumer declares the shared reply queue used by AMQPTransport
+// and forwards every delivery on it into replyrouter by correlation id. It
+// must be started once, after the AMQP connection has been established,
+// before any request is dispatched via AMQPTransport
+func StartReplyConsumer(ctx context.Context) error {
+	queue, err := vars.AMQP.Channel.QueueDeclare(replyQueueName, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := vars.AMQP.Channel.Consume(queue.Name, "water-usage-forecasts.calculation-reply-router", true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go replyrouter.Start(ctx, deliveries)
+
+	return nil
+}
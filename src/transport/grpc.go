@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"microservice/structs"
+	"microservice/transport/calculationpb"
+	"microservice/vars"
+)
+
+// GRPCTransport dispatches calculation requests to the Python calculation
+// module over gRPC instead of AMQP. Unlike AMQPTransport it does not declare
+// or tear down any broker resources per request, and Calculate is a
+// server-streaming RPC so partial results can be forwarded as they arrive
+// instead of only ever producing one final message
+type GRPCTransport struct {
+	// Target is the "host:port" address of the calculation module's gRPC
+	// server. When empty, vars.Environment["CALCULATION_MODULE_GRPC_TARGET"]
+	// is used instead
+	Target string
+}
+
+func (t *GRPCTransport) target() string {
+	if t.Target != "" {
+		return t.Target
+	}
+	return vars.Environment["CALCULATION_MODULE_GRPC_TARGET"]
+}
+
+// Submit opens a new connection to the calculation module for every request
+// and streams back the results it produces. Connections are short-lived
+// since the calculation module is addressed directly instead of through a
+// broker, so there is no persistent client to share across requests
+func (t *GRPCTransport) Submit(ctx context.Context, request structs.CalculationRequest, correlationId string) (<-chan Result, error) {
+	conn, err := grpc.DialContext(ctx, t.target(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client := calculationpb.NewCalculationServiceClient(conn)
+	stream, err := client.Calculate(ctx, &calculationpb.CalculationRequest{
+		Model:           string(request.Model),
+		Keys:            request.Keys,
+		ConsumerGroups:  request.ConsumerGroups,
+		ForecastedYears: int32(request.ForecastedYears),
+		CorrelationId:   correlationId,
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	results := make(chan Result, 1)
+	go func() {
+		defer close(results)
+		defer conn.Close()
+
+		for {
+			message, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				return
+			}
+			results <- Result{Body: message.Body, Partial: message.Partial}
+			if !message.Partial {
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
@@ -0,0 +1,38 @@
+// Package transport abstracts how forecast calculation requests are
+// dispatched to the (Python) calculation module. tasks.dispatch and
+// routes.NewForecastStream call Select for every dispatched request, which
+// picks an implementation based on CALCULATION_TRANSPORT so AMQP and gRPC
+// deployments can be switched between without a code change
+package transport
+
+import (
+	"context"
+
+	"microservice/structs"
+	"microservice/vars"
+)
+
+// Result is a single message produced by the calculation module in response
+// to a forecast calculation. AMQPTransport only ever sends one, final
+// Result; GRPCTransport may send several partial ones before the final one
+type Result struct {
+	Body    []byte
+	Partial bool
+}
+
+// ForecastTransport dispatches a calculation request to the calculation
+// module and returns a channel of results. The channel is closed once the
+// final result has been sent, ctx is done, or the transport gives up
+type ForecastTransport interface {
+	Submit(ctx context.Context, request structs.CalculationRequest, correlationId string) (<-chan Result, error)
+}
+
+// Select returns the ForecastTransport configured via the
+// CALCULATION_TRANSPORT environment variable, defaulting to AMQPTransport
+// when it is unset or holds an unrecognized value
+func Select() ForecastTransport {
+	if vars.Environment["CALCULATION_TRANSPORT"] == "grpc" {
+		return &GRPCTransport{}
+	}
+	return &AMQPTransport{}
+}
@@ -0,0 +1,38 @@
+// Package utils contains small helper functions which are used throughout
+// the microservice and do not belong to a more specific package
+package utils
+
+import (
+	"fmt"
+	"net"
+)
+
+// ArrayContains checks whether the value v is contained in the array a
+func ArrayContains[T comparable](a []T, v T) bool {
+	for _, entry := range a {
+		if entry == v {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalIPv4Address returns the first non-loopback IPv4 address found on the
+// machine the microservice is running on. It is used to register the
+// microservice instance at the api gateway
+func LocalIPv4Address() (string, error) {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, address := range addresses {
+		ipNet, ok := address.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ipv4 := ipNet.IP.To4(); ipv4 != nil {
+			return ipv4.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no local ipv4 address found")
+}
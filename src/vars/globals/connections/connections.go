@@ -0,0 +1,29 @@
+// Package connections contains the connections used by the legacy
+// "NewForecast" handler which predates the vars package. It is kept around
+// until the handler is reconciled with the active ForecastRequest handler
+package connections
+
+import (
+	"database/sql"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DbConnection contains the connection to the postgres database
+var DbConnection *sql.DB
+
+// amqpConnection bundles the AMQP connection, the channel used to publish
+// the calculation requests and the shared callback queue used to receive
+// the responses of the calculation module
+type amqpConnection struct {
+	Connection    *amqp.Connection
+	Channel       *amqp.Channel
+	CallbackQueue amqp.Queue
+	// Messages receives every delivery made to the callback queue. Since the
+	// queue is shared between all requests, every handler waiting for a
+	// response has to filter the deliveries by their correlation id
+	Messages <-chan amqp.Delivery
+}
+
+// AMQP contains the shared connection to the message broker
+var AMQP amqpConnection
@@ -0,0 +1,19 @@
+// Package globals contains the global variables used by the legacy
+// "NewForecast" handler which predates the vars package. It is kept around
+// until the handler is reconciled with the active ForecastRequest handler
+package globals
+
+import (
+	"github.com/qustavo/dotsql"
+	"github.com/rs/zerolog"
+)
+
+// HttpLogger is the logger used by the legacy forecast handler
+var HttpLogger zerolog.Logger
+
+// Queries contains the prepared sql queries used by the legacy forecast
+// handler
+var Queries *dotsql.DotSql
+
+// Environment contains the environment variables resolved on startup
+var Environment map[string]string
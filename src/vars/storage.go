@@ -0,0 +1,44 @@
+package vars
+
+import (
+	"context"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ConnectObjectStorage sets up the connection to the S3-compatible object
+// store which is used to cache already calculated forecast results. The
+// connection parameters are read from the configured environment variables
+func ConnectObjectStorage() error {
+	endpoint := Environment["S3_ENDPOINT"]
+	accessKey := Environment["S3_ACCESS_KEY"]
+	secretKey := Environment["S3_SECRET_KEY"]
+	useSSL := Environment["S3_USE_SSL"] == "true"
+	ForecastResultBucket = Environment["S3_FORECAST_BUCKET"]
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return err
+	}
+	ObjectStorage = client
+
+	return ensureForecastResultBucket()
+}
+
+// ensureForecastResultBucket creates the bucket used to cache forecast
+// results if it does not already exist
+func ensureForecastResultBucket() error {
+	ctx := context.Background()
+	exists, err := ObjectStorage.BucketExists(ctx, ForecastResultBucket)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return ObjectStorage.MakeBucket(ctx, ForecastResultBucket, minio.MakeBucketOptions{})
+}
@@ -0,0 +1,70 @@
+// Package vars contains the global variables shared across the whole
+// microservice (database connection, prepared queries, logger and the
+// resolved runtime configuration)
+package vars
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/qustavo/dotsql"
+	"github.com/rs/zerolog"
+
+	"microservice/structs"
+)
+
+// PostgresConnection contains the connection to the postgres database which
+// is used to validate the requested municipality keys and consumer groups
+var PostgresConnection *sql.DB
+
+// SqlQueries contains the prepared sql queries which are loaded from the
+// queries folder on startup
+var SqlQueries *dotsql.DotSql
+
+// HttpLogger is the logger used for logging the incoming http requests and
+// everything happening while handling them
+var HttpLogger zerolog.Logger
+
+// ListenPort contains the port on which the microservice will listen for
+// incoming requests
+var ListenPort int
+
+// ServiceName contains the name under which the microservice registers
+// itself at the api gateway
+var ServiceName string
+
+// Environment contains the environment variables which configure the
+// microservice and are not already resolved into one of the variables above
+var Environment map[string]string
+
+// ObjectStorage contains the connection to the S3-compatible object store
+// which is used to cache already calculated forecasts
+var ObjectStorage *minio.Client
+
+// ForecastResultBucket contains the name of the bucket in which the cached
+// forecast results are stored. Only the results are stored there; the
+// request payloads are not
+var ForecastResultBucket string
+
+// AMQP contains the connection and channel used to dispatch forecast
+// calculation requests to the calculation module
+var AMQP structs.AMQP
+
+// AuthMode selects how incoming requests are authorized. "header" (the
+// default) trusts the X-Authenticated-User/X-Authenticated-Groups headers
+// injected by the upstream Kong gateway. "oidc" verifies an
+// `Authorization: Bearer <jwt>` token against Environment["OIDC_ISSUER"]
+// instead, for deployments which do not sit behind that gateway
+var AuthMode = "header"
+
+// ActiveForecastRequests is incremented on entry and decremented on return of
+// every ForecastRequest invocation so that a graceful shutdown can wait for
+// in-flight calculations to finish before tearing down the AMQP connection
+var ActiveForecastRequests sync.WaitGroup
+
+// Draining is set once the microservice has started shutting down. The
+// readiness healthcheck reports not-ready while this is set so the gateway
+// stops routing new requests to the draining instance
+var Draining atomic.Bool